@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// CELInterceptor provides a webhook to intercept and pre-process events
+type CELInterceptor struct {
+	// Filter is a CEL expression that is evaluated against the incoming
+	// event; if it does not evaluate to true, the event is rejected.
+	Filter string `json:"filter,omitempty"`
+
+	// SecretRef points at a Kubernetes Secret that holds the shared secret
+	// used by the verifyHMAC()/verifyGitLabToken() CEL overloads to validate
+	// the signature of the incoming request. It is resolved
+	// relative to the EventListener's namespace when Namespace is empty.
+	SecretRef *SecretRef `json:"secretRef,omitempty"`
+
+	// Overlays is an optional list of CEL expressions that are evaluated
+	// against the incoming body/header and written into the outgoing body,
+	// only once Filter has passed, so that TriggerBindings can
+	// reference fields that do not exist on the original payload.
+	Overlays []CELOverlay `json:"overlays,omitempty"`
+
+	// Projections is an optional list of JMESPath expressions that are
+	// evaluated against the incoming body and written into the outgoing
+	// body under the top-level "extensions" key, alongside any Overlays.
+	Projections []CELProjection `json:"projections,omitempty"`
+}
+
+// CELOverlay provides a way to modify the given body using a CEL expression
+type CELOverlay struct {
+	Key        string `json:"key,omitempty"`
+	Expression string `json:"expression,omitempty"`
+}
+
+// CELProjection extracts a value from the body using a JMESPath expression
+// and attaches it to the outgoing body under extensions.<Name>
+type CELProjection struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// SecretRef contains the information required to reference a single secret string
+// This is needed because the other secretRef types are not cross-namespace and do not
+// actually contain the "SecretName" field, which allows us to access a single secret value.
+type SecretRef struct {
+	SecretKey  string `json:"secretKey,omitempty"`
+	SecretName string `json:"secretName,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
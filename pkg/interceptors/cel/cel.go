@@ -0,0 +1,675 @@
+/*
+Copyright 2019 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter/functions"
+	"github.com/jmespath/go-jmespath"
+	"github.com/tektoncd/triggers/pkg/interceptors"
+	"github.com/tidwall/sjson"
+	"go.uber.org/zap"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	triggersv1 "github.com/tektoncd/triggers/pkg/apis/triggers/v1alpha1"
+)
+
+// hmacHashes maps the algorithm name accepted by hmac()/verifyHMAC (e.g.
+// "sha1", "sha256") to the hash.Hash constructor used to compute the MAC.
+var hmacHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// Interceptor implements a CEL based interceptor, that uses CEL expressions
+// against the incoming body and headers to match, if the expression returns
+// a true value, then the interception is "successful".
+type Interceptor struct {
+	KubeClientSet          kubernetes.Interface
+	Logger                 *zap.SugaredLogger
+	CEL                    *triggersv1.CELInterceptor
+	EventListenerNamespace string
+}
+
+// NewInterceptor creates a prepopulated Interceptor.
+func NewInterceptor(cel *triggersv1.CELInterceptor, k kubernetes.Interface, ns string, l *zap.SugaredLogger) interceptors.Interceptor {
+	return &Interceptor{
+		Logger:                 l,
+		CEL:                    cel,
+		KubeClientSet:          k,
+		EventListenerNamespace: ns,
+	}
+}
+
+// ExecuteTrigger is an implementation of the Interceptor interface.
+func (w *Interceptor) ExecuteTrigger(request *http.Request) (*http.Response, error) {
+	env, err := makeCelEnv()
+	if err != nil {
+		return nil, fmt.Errorf("error creating cel environment: %w", err)
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("error getting request body: %w", err)
+	}
+	defer body.Close()
+	payload, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	evalContext, err := makeEvalContext(payload, request)
+	if err != nil {
+		return nil, fmt.Errorf("error making the evaluation context: %w", err)
+	}
+
+	var secretToken []byte
+	if w.CEL.SecretRef != nil {
+		secretToken, err = interceptors.GetSecretToken(request.Context(), w.KubeClientSet, w.CEL.SecretRef, w.EventListenerNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("error getting secret token: %w", err)
+		}
+	}
+	fns := signatureFunctions(secretToken, payload)
+
+	if w.CEL.Filter != "" {
+		out, err := evaluate(w.CEL.Filter, env, evalContext, fns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate expression '%s': %w", w.CEL.Filter, err)
+		}
+
+		if out != types.True {
+			return nil, fmt.Errorf("expression %s did not return true", w.CEL.Filter)
+		}
+	}
+
+	for _, overlay := range w.CEL.Overlays {
+		val, err := evaluate(overlay.Expression, env, evalContext, fns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate overlay expression '%s': %w", overlay.Expression, err)
+		}
+
+		raw, err := overlayValueToJSON(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert overlay result to JSON: %w", err)
+		}
+
+		payload, err = sjson.SetRawBytes(payload, overlay.Key, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set overlay for key '%s' to '%s': %w", overlay.Key, val, err)
+		}
+	}
+
+	for _, projection := range w.CEL.Projections {
+		result, err := jmespath.Search(projection.Path, evalContext["body"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate projection '%s': %w", projection.Path, err)
+		}
+
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal projection '%s' result: %w", projection.Name, err)
+		}
+
+		payload, err = sjson.SetRawBytes(payload, "extensions."+projection.Name, raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set projection for name '%s': %w", projection.Name, err)
+		}
+	}
+
+	return &http.Response{
+		Header: request.Header,
+		Body:   ioutil.NopCloser(bytes.NewReader(payload)),
+	}, nil
+}
+
+// overlayValueToJSON converts the result of evaluating an overlay expression
+// into the raw JSON bytes that get spliced into the outgoing body at the
+// overlay's dotted Key, preserving strings, numbers, bools, lists and maps.
+func overlayValueToJSON(val ref.Val) ([]byte, error) {
+	switch val.(type) {
+	case types.String, types.Double, types.Int, types.Bool, traits.Lister, traits.Mapper:
+		raw, err := val.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+		if err != nil {
+			return nil, err
+		}
+		marshaled, err := protojson.Marshal(raw.(*structpb.Value))
+		if err != nil {
+			return nil, err
+		}
+		// protojson spaces out list/object members; compact it back down so
+		// it can be spliced into the surrounding body without stray spaces.
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, marshaled); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported overlay result type %T", val)
+	}
+}
+
+func evaluate(expr string, env *cel.Env, data map[string]interface{}, opts ...cel.ProgramOption) (ref.Val, error) {
+	parsed, issues := env.Parse(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	checked, issues := env.Check(parsed)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	prg, err := env.Program(checked, append([]cel.ProgramOption{embeddedFunctions()}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _, err := prg.Eval(data)
+	return out, err
+}
+
+func embeddedFunctions() cel.ProgramOption {
+	return cel.Functions(
+		&functions.Overload{
+			Operator: "match",
+			Function: matchHeader},
+		&functions.Overload{
+			Operator: "split",
+			Binary:   splitString},
+		&functions.Overload{
+			Operator: "substring",
+			Function: substringString},
+		&functions.Overload{
+			Operator: "compareStrings",
+			Binary:   compareStrings},
+		&functions.Overload{
+			Operator: "parseJSON",
+			Unary:    parseJSONString},
+		&functions.Overload{
+			Operator: "parseYAML",
+			Unary:    parseYAMLString},
+		&functions.Overload{
+			Operator: "parseURL",
+			Unary:    parseURLString},
+		&functions.Overload{
+			Operator: "jsonpath",
+			Binary:   jsonpathLookup},
+	)
+
+}
+
+// signatureFunctions returns the hmac/hex/base64/verifyHMAC/verifyGitLabToken
+// overloads. secret and rawBody are bound from the current request so that
+// the CEL expression never needs to carry the shared secret inline.
+func signatureFunctions(secret []byte, rawBody []byte) cel.ProgramOption {
+	return cel.Functions(
+		&functions.Overload{
+			Operator: "hmac",
+			Function: hmacBytes,
+		},
+		&functions.Overload{
+			Operator: "hex",
+			Unary:    hexEncode,
+		},
+		&functions.Overload{
+			Operator: "base64",
+			Unary:    base64Encode,
+		},
+		&functions.Overload{
+			Operator: "secureCompare",
+			Function: secureCompare,
+		},
+		&functions.Overload{
+			Operator: "verifyHMAC",
+			Function: func(vals ...ref.Val) ref.Val {
+				return verifyHMAC(secret, rawBody, vals...)
+			},
+		},
+		&functions.Overload{
+			Operator: "verifyGitLabToken",
+			Binary: func(lhs, rhs ref.Val) ref.Val {
+				return verifyGitLabToken(secret, lhs, rhs)
+			},
+		},
+	)
+}
+
+func makeCelEnv() (*cel.Env, error) {
+	mapStrDyn := decls.NewMapType(decls.String, decls.Dyn)
+	listStr := decls.NewListType(decls.String)
+	return cel.NewEnv(
+		cel.Declarations(
+			decls.NewIdent("body", mapStrDyn, nil),
+			decls.NewIdent("header", mapStrDyn, nil),
+			decls.NewIdent("body_bytes", decls.Bytes, nil),
+			decls.NewFunction("parseJSON",
+				decls.NewOverload("parseJSON_string",
+					[]*exprpb.Type{decls.String}, mapStrDyn)),
+			decls.NewFunction("parseYAML",
+				decls.NewOverload("parseYAML_string",
+					[]*exprpb.Type{decls.String}, mapStrDyn)),
+			decls.NewFunction("parseURL",
+				decls.NewOverload("parseURL_string",
+					[]*exprpb.Type{decls.String}, mapStrDyn)),
+			decls.NewFunction("jsonpath",
+				decls.NewInstanceOverload("jsonpath_map_string",
+					[]*exprpb.Type{mapStrDyn, decls.String}, decls.Dyn)),
+			decls.NewFunction("match",
+				decls.NewInstanceOverload("match_map_string_string",
+					[]*exprpb.Type{mapStrDyn, decls.String, decls.String}, decls.Bool)),
+			decls.NewFunction("split",
+				decls.NewInstanceOverload("split_string_string",
+					[]*exprpb.Type{decls.String, decls.String}, listStr)),
+			decls.NewFunction("substring",
+				decls.NewInstanceOverload("substring_string_int_int",
+					[]*exprpb.Type{decls.String, decls.Int, decls.Int}, decls.String)),
+			decls.NewFunction("compareStrings",
+				decls.NewInstanceOverload("compareStrings_string_string",
+					[]*exprpb.Type{decls.String, decls.String}, decls.Int)),
+			decls.NewFunction("hmac",
+				decls.NewOverload("hmac_string_bytes_bytes",
+					[]*exprpb.Type{decls.String, decls.Bytes, decls.Bytes}, decls.Bytes)),
+			decls.NewFunction("hex",
+				decls.NewOverload("hex_bytes",
+					[]*exprpb.Type{decls.Bytes}, decls.String)),
+			decls.NewFunction("base64",
+				decls.NewOverload("base64_bytes",
+					[]*exprpb.Type{decls.Bytes}, decls.String)),
+			decls.NewFunction("secureCompare",
+				decls.NewOverload("secureCompare_bytes_bytes",
+					[]*exprpb.Type{decls.Bytes, decls.Bytes}, decls.Bool)),
+			decls.NewFunction("verifyHMAC",
+				decls.NewInstanceOverload("verifyHMAC_map_string_string",
+					[]*exprpb.Type{mapStrDyn, decls.String, decls.String}, decls.Bool)),
+			decls.NewFunction("verifyGitLabToken",
+				decls.NewInstanceOverload("verifyGitLabToken_map_string",
+					[]*exprpb.Type{mapStrDyn, decls.String}, decls.Bool))))
+}
+
+// makeEvalContext decodes body into the "body" map exposed to CEL
+// expressions, branching on the request's Content-Type. The raw bytes are
+// always additionally exposed as "body_bytes" for content types that the
+// parser below doesn't understand, or that callers want to decode
+// themselves via parseJSON/parseYAML.
+func makeEvalContext(body []byte, r *http.Request) (map[string]interface{}, error) {
+	bodyMap, err := decodeBody(body, mediaType(r))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"body": bodyMap, "header": r.Header, "body_bytes": body}, nil
+}
+
+func mediaType(r *http.Request) string {
+	contentType := r.Header.Get("Content-Type")
+	return strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+}
+
+func decodeBody(body []byte, contentType string) (interface{}, error) {
+	switch contentType {
+	case "", "application/json":
+		var jsonMap map[string]interface{}
+		if err := json.Unmarshal(body, &jsonMap); err != nil {
+			return nil, err
+		}
+		return jsonMap, nil
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse form-encoded body: %w", err)
+		}
+		return map[string][]string(values), nil
+	case "application/yaml", "text/yaml":
+		var yamlMap map[string]interface{}
+		if err := yaml.Unmarshal(body, &yamlMap); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML body: %w", err)
+		}
+		return yamlMap, nil
+	default:
+		// application/octet-stream and anything else we don't recognise:
+		// leave body empty, the raw bytes are still available as body_bytes.
+		return map[string]interface{}{}, nil
+	}
+}
+
+func matchHeader(vals ...ref.Val) ref.Val {
+	h, err := vals[0].ConvertToNative(reflect.TypeOf(http.Header{}))
+	if err != nil {
+		return types.NewErr("failed to convert to http.Header: %w", err)
+	}
+
+	key, ok := vals[1].(types.String)
+	if !ok {
+		return types.ValOrErr(key, "unexpected type '%v' passed to match", vals[1].Type())
+	}
+
+	val, ok := vals[2].(types.String)
+	if !ok {
+		return types.ValOrErr(val, "unexpected type '%v' passed to match", vals[2].Type())
+	}
+
+	return types.Bool(h.(http.Header).Get(string(key)) == string(val))
+
+}
+
+func splitString(lhs, rhs ref.Val) ref.Val {
+	str, ok := lhs.(types.String)
+	if !ok {
+		return types.ValOrErr(str, "unexpected type '%v' passed to split", lhs.Type())
+	}
+
+	sep, ok := rhs.(types.String)
+	if !ok {
+		return types.ValOrErr(sep, "unexpected type '%v' passed to split", rhs.Type())
+	}
+
+	return types.NewStringList(types.NewRegistry(), strings.Split(string(str), string(sep)))
+}
+
+// substringString implements the body.value.substring(start, end) overload,
+// clamping start/end to the bounds of the string instead of panicking.
+func substringString(vals ...ref.Val) ref.Val {
+	str, ok := vals[0].(types.String)
+	if !ok {
+		return types.ValOrErr(str, "unexpected type '%v' passed to substring", vals[0].Type())
+	}
+
+	start, ok := vals[1].(types.Int)
+	if !ok {
+		return types.ValOrErr(start, "unexpected type '%v' passed to substring", vals[1].Type())
+	}
+
+	end, ok := vals[2].(types.Int)
+	if !ok {
+		return types.ValOrErr(end, "unexpected type '%v' passed to substring", vals[2].Type())
+	}
+
+	s := string(str)
+	lo, hi := int(start), int(end)
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > len(s) {
+		hi = len(s)
+	}
+	if lo > hi {
+		return types.NewErr("substring: start %d is after end %d", start, end)
+	}
+
+	return types.String(s[lo:hi])
+}
+
+// compareStrings mirrors strings.Compare, returning -1, 0 or 1, so that CEL
+// filters can do ordering comparisons that == alone cannot express.
+func compareStrings(lhs, rhs ref.Val) ref.Val {
+	a, ok := lhs.(types.String)
+	if !ok {
+		return types.ValOrErr(a, "unexpected type '%v' passed to compareStrings", lhs.Type())
+	}
+
+	b, ok := rhs.(types.String)
+	if !ok {
+		return types.ValOrErr(b, "unexpected type '%v' passed to compareStrings", rhs.Type())
+	}
+
+	return types.Int(strings.Compare(string(a), string(b)))
+}
+
+func parseJSONString(val ref.Val) ref.Val {
+	str, ok := val.(types.String)
+	if !ok {
+		return types.ValOrErr(str, "unexpected type '%v' passed to parseJSON", val.Type())
+	}
+	decoded := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(str), &decoded); err != nil {
+		return types.NewErr("failed to decode '%v' in parseJSON: %w", str, err)
+	}
+	return types.NewDynamicMap(types.NewRegistry(), decoded)
+}
+
+func parseYAMLString(val ref.Val) ref.Val {
+	str, ok := val.(types.String)
+	if !ok {
+		return types.ValOrErr(str, "unexpected type '%v' passed to parseYAML", val.Type())
+	}
+	decoded := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &decoded); err != nil {
+		return types.NewErr("failed to decode '%v' in parseYAML: %w", str, err)
+	}
+	return types.NewDynamicMap(types.NewRegistry(), decoded)
+}
+
+func parseURLString(val ref.Val) ref.Val {
+	str, ok := val.(types.String)
+	if !ok {
+		return types.ValOrErr(str, "unexpected type '%v' passed to parseURL", val.Type())
+	}
+
+	parsed, err := url.Parse(string(str))
+	if err != nil {
+		return types.NewErr("failed to decode '%v' in parseURL: %w", str, err)
+	}
+
+	m := map[string]interface{}{
+		"scheme":   parsed.Scheme,
+		"host":     parsed.Host,
+		"path":     parsed.Path,
+		"rawQuery": parsed.RawQuery,
+		"fragment": parsed.Fragment,
+		"query":    parsed.Query(),
+	}
+	if parsed.User != nil {
+		pass, _ := parsed.User.Password()
+		m["auth"] = map[string]string{
+			"username": parsed.User.Username(),
+			"password": pass,
+		}
+	}
+	return types.NewDynamicMap(types.NewRegistry(), m)
+}
+
+// jsonpathLookup implements the b.jsonpath('expr') instance overload,
+// evaluating a JMESPath expression against the map it is called on. A path
+// that matches nothing yields CEL null rather than an error, matching
+// JMESPath's own semantics.
+func jsonpathLookup(lhs ref.Val, rhs ref.Val) ref.Val {
+	m, err := lhs.ConvertToNative(reflect.TypeOf(map[string]interface{}{}))
+	if err != nil {
+		return types.NewErr("failed to convert '%v' for jsonpath: %w", lhs, err)
+	}
+
+	path, ok := rhs.(types.String)
+	if !ok {
+		return types.ValOrErr(path, "unexpected type '%v' passed to jsonpath", rhs.Type())
+	}
+
+	result, err := jmespath.Search(string(path), m)
+	if err != nil {
+		return types.NewErr("failed to evaluate jsonpath '%v': %w", path, err)
+	}
+
+	return nativeToVal(result)
+}
+
+// nativeToVal adapts the result of a JMESPath search, which may be nil, a
+// scalar, a []interface{} or a map[string]interface{}, into a CEL value.
+func nativeToVal(v interface{}) ref.Val {
+	switch val := v.(type) {
+	case nil:
+		return types.NullValue
+	case map[string]interface{}:
+		return types.NewDynamicMap(types.NewRegistry(), val)
+	case []interface{}:
+		return types.NewDynamicList(types.NewRegistry(), val)
+	default:
+		return types.DefaultTypeAdapter.NativeToValue(val)
+	}
+}
+
+// hmacBytes computes the HMAC of data under key using the named algorithm
+// (one of "sha1" or "sha256"), returning the raw digest.
+func hmacBytes(vals ...ref.Val) ref.Val {
+	algorithm, ok := vals[0].(types.String)
+	if !ok {
+		return types.ValOrErr(algorithm, "unexpected type '%v' passed to hmac", vals[0].Type())
+	}
+
+	key, ok := vals[1].(types.Bytes)
+	if !ok {
+		return types.ValOrErr(key, "unexpected type '%v' passed to hmac", vals[1].Type())
+	}
+
+	data, ok := vals[2].(types.Bytes)
+	if !ok {
+		return types.ValOrErr(data, "unexpected type '%v' passed to hmac", vals[2].Type())
+	}
+
+	newHash, ok := hmacHashes[string(algorithm)]
+	if !ok {
+		return types.NewErr("unsupported hmac algorithm '%s'", algorithm)
+	}
+
+	mac := hmac.New(newHash, key)
+	mac.Write(data)
+	return types.Bytes(mac.Sum(nil))
+}
+
+func hexEncode(val ref.Val) ref.Val {
+	b, ok := val.(types.Bytes)
+	if !ok {
+		return types.ValOrErr(b, "unexpected type '%v' passed to hex", val.Type())
+	}
+	return types.String(hex.EncodeToString(b))
+}
+
+func base64Encode(val ref.Val) ref.Val {
+	b, ok := val.(types.Bytes)
+	if !ok {
+		return types.ValOrErr(b, "unexpected type '%v' passed to base64", val.Type())
+	}
+	return types.String(base64.StdEncoding.EncodeToString(b))
+}
+
+// secureCompare compares two byte strings in constant time, so that CEL
+// expressions built on top of hmac()/hex() do not leak timing information
+// about the expected signature.
+func secureCompare(vals ...ref.Val) ref.Val {
+	a, ok := vals[0].(types.Bytes)
+	if !ok {
+		return types.ValOrErr(a, "unexpected type '%v' passed to secureCompare", vals[0].Type())
+	}
+	b, ok := vals[1].(types.Bytes)
+	if !ok {
+		return types.ValOrErr(b, "unexpected type '%v' passed to secureCompare", vals[1].Type())
+	}
+	return types.Bool(hmac.Equal(a, b))
+}
+
+// verifyHMAC implements the header.verifyHMAC('<header-name>', '<algorithm>')
+// CEL overload. It reads the signature out of the named header, tolerating
+// the "<algorithm>=" prefix convention used by GitHub, and compares it
+// against the HMAC of rawBody computed with secret in constant time.
+func verifyHMAC(secret []byte, rawBody []byte, vals ...ref.Val) ref.Val {
+	h, err := vals[0].ConvertToNative(reflect.TypeOf(http.Header{}))
+	if err != nil {
+		return types.NewErr("failed to convert to http.Header: %w", err)
+	}
+
+	headerName, ok := vals[1].(types.String)
+	if !ok {
+		return types.ValOrErr(headerName, "unexpected type '%v' passed to verifyHMAC", vals[1].Type())
+	}
+
+	algorithm, ok := vals[2].(types.String)
+	if !ok {
+		return types.ValOrErr(algorithm, "unexpected type '%v' passed to verifyHMAC", vals[2].Type())
+	}
+
+	if len(secret) == 0 {
+		return types.NewErr("verifyHMAC: CELInterceptor has no secretRef configured")
+	}
+
+	newHash, ok := hmacHashes[string(algorithm)]
+	if !ok {
+		return types.NewErr("unsupported hmac algorithm '%s'", algorithm)
+	}
+
+	signature := h.(http.Header).Get(string(headerName))
+	if signature == "" {
+		return types.False
+	}
+	signature = strings.TrimPrefix(signature, string(algorithm)+"=")
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return types.False
+	}
+
+	mac := hmac.New(newHash, secret)
+	mac.Write(rawBody)
+
+	return types.Bool(hmac.Equal(decoded, mac.Sum(nil)))
+}
+
+// verifyGitLabToken implements the header.verifyGitLabToken('<header-name>')
+// CEL overload. Unlike GitHub, GitLab sends the shared secret directly in
+// the header rather than an HMAC signature, so this is a constant-time
+// string comparison against the configured secret.
+func verifyGitLabToken(secret []byte, vals ...ref.Val) ref.Val {
+	h, err := vals[0].ConvertToNative(reflect.TypeOf(http.Header{}))
+	if err != nil {
+		return types.NewErr("failed to convert to http.Header: %w", err)
+	}
+
+	headerName, ok := vals[1].(types.String)
+	if !ok {
+		return types.ValOrErr(headerName, "unexpected type '%v' passed to verifyGitLabToken", vals[1].Type())
+	}
+
+	if len(secret) == 0 {
+		return types.NewErr("verifyGitLabToken: CELInterceptor has no secretRef configured")
+	}
+
+	token := h.(http.Header).Get(string(headerName))
+	if token == "" {
+		return types.False
+	}
+
+	return types.Bool(hmac.Equal([]byte(token), secret))
+}
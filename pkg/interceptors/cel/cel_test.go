@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 
@@ -12,8 +13,25 @@ import (
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/tektoncd/pipeline/pkg/logging"
 	triggersv1 "github.com/tektoncd/triggers/pkg/apis/triggers/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
+const testNS = "testing-ns"
+
+func makeSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNS,
+			Name:      "test-secret",
+		},
+		Data: map[string][]byte{
+			"token": []byte("secret"),
+		},
+	}
+}
+
 func TestInterceptor_ExecuteTrigger(t *testing.T) {
 	type args struct {
 		payload []byte
@@ -119,23 +137,308 @@ func TestInterceptor_ExecuteTrigger(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid sha256 HMAC signature",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyHMAC('X-Hub-Signature-256', 'sha256')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"testing"}`),
+			},
+			want:    []byte(`{"value":"testing"}`),
+			wantErr: false,
+		},
+		{
+			name: "valid sha1 HMAC signature",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyHMAC('X-Hub-Signature', 'sha1')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"testing"}`),
+			},
+			want:    []byte(`{"value":"testing"}`),
+			wantErr: false,
+		},
+		{
+			name: "bad HMAC signature",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyHMAC('X-Hub-Signature-256', 'sha256')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"tampered"}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing HMAC header",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyHMAC('X-Missing-Signature', 'sha256')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"testing"}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "verifyHMAC without a secretRef configured",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyHMAC('X-Hub-Signature-256', 'sha256')",
+			},
+			args: args{
+				payload: []byte(`{"value":"testing"}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "verifyGitLabToken without a secretRef configured",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyGitLabToken('X-Gitlab-Token')",
+			},
+			args: args{
+				payload: []byte(`{}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid GitLab token",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyGitLabToken('X-Gitlab-Token')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{}`),
+			},
+			want:    []byte(`{}`),
+			wantErr: false,
+		},
+		{
+			name: "invalid GitLab token",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "header.verifyGitLabToken('X-Gitlab-Token')",
+				SecretRef: &triggersv1.SecretRef{
+					SecretName: "test-secret",
+					SecretKey:  "token",
+					Namespace:  testNS,
+				},
+			},
+			args: args{
+				payload: []byte(`{}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "single overlay with matching filter",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value == 'test'",
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "new", Expression: "body.value"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"test"}`),
+			},
+			want:    []byte(`{"value":"test","new":"test"}`),
+			wantErr: false,
+		},
+		{
+			name: "overlay with no filter preserves unrelated fields",
+			CEL: &triggersv1.CELInterceptor{
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "branch", Expression: "body.ref.split('/')[2]"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"ref":"refs/head/master","name":"testing"}`),
+			},
+			want:    []byte(`{"ref":"refs/head/master","name":"testing","branch":"master"}`),
+			wantErr: false,
+		},
+		{
+			name: "overlay using substring",
+			CEL: &triggersv1.CELInterceptor{
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "truncated_sha", Expression: "body.sha.substring(0, 7)"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"sha":"ec26c3e57ca3a959ca5aad62de7213c562f8c821"}`),
+			},
+			want:    []byte(`{"sha":"ec26c3e57ca3a959ca5aad62de7213c562f8c821","truncated_sha":"ec26c3e"}`),
+			wantErr: false,
+		},
+		{
+			name: "failing filter short-circuits before overlay runs",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value == 'nope'",
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "new", Expression: "body.value"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"test"}`),
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlay with a numeric result",
+			CEL: &triggersv1.CELInterceptor{
+				// body.count decodes from JSON as a CEL double, so the
+				// literal must be a double too: CEL has no mixed int/double
+				// "+" overload.
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "next_count", Expression: "body.count + 1.0"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"count":1}`),
+			},
+			want:    []byte(`{"count":1,"next_count":2}`),
+			wantErr: false,
+		},
+		{
+			name: "overlay with a boolean result",
+			CEL: &triggersv1.CELInterceptor{
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "ok", Expression: "body.value == 'test'"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"test"}`),
+			},
+			want:    []byte(`{"value":"test","ok":true}`),
+			wantErr: false,
+		},
+		{
+			name: "overlay with a list result",
+			CEL: &triggersv1.CELInterceptor{
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "parts", Expression: "body.ref.split('/')"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"ref":"refs/head/master"}`),
+			},
+			want:    []byte(`{"ref":"refs/head/master","parts":["refs","head","master"]}`),
+			wantErr: false,
+		},
+		{
+			name: "overlay with a map result",
+			CEL: &triggersv1.CELInterceptor{
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "ref_copy", Expression: "body.ref"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"ref":{"name":"master"}}`),
+			},
+			want:    []byte(`{"ref":{"name":"master"},"ref_copy":{"name":"master"}}`),
+			wantErr: false,
+		},
+		{
+			name: "projection extracts a nested field into extensions",
+			CEL: &triggersv1.CELInterceptor{
+				Projections: []triggersv1.CELProjection{
+					{Name: "branch", Path: "ref.name"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"ref":{"name":"master"}}`),
+			},
+			want:    []byte(`{"ref":{"name":"master"},"extensions":{"branch":"master"}}`),
+			wantErr: false,
+		},
+		{
+			name: "projection slices a list",
+			CEL: &triggersv1.CELInterceptor{
+				Projections: []triggersv1.CELProjection{
+					{Name: "first_two", Path: "commits[0:2].sha"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"commits":[{"sha":"a"},{"sha":"b"},{"sha":"c"}]}`),
+			},
+			want:    []byte(`{"commits":[{"sha":"a"},{"sha":"b"},{"sha":"c"}],"extensions":{"first_two":["a","b"]}}`),
+			wantErr: false,
+		},
+		{
+			name: "projection with a missing path yields null instead of an error",
+			CEL: &triggersv1.CELInterceptor{
+				Projections: []triggersv1.CELProjection{
+					{Name: "missing", Path: "does.not.exist"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"test"}`),
+			},
+			want:    []byte(`{"value":"test","extensions":{"missing":null}}`),
+			wantErr: false,
+		},
+		{
+			name: "projection combines with a filter and an overlay",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value == 'test'",
+				Overlays: []triggersv1.CELOverlay{
+					{Key: "new", Expression: "body.value"},
+				},
+				Projections: []triggersv1.CELProjection{
+					{Name: "value", Path: "value"},
+				},
+			},
+			args: args{
+				payload: []byte(`{"value":"test"}`),
+			},
+			want:    []byte(`{"value":"test","new":"test","extensions":{"value":"test"}}`),
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger, _ := logging.NewLogger("", "")
 			w := &Interceptor{
-				CEL:    tt.CEL,
-				Logger: logger,
+				CEL:           tt.CEL,
+				Logger:        logger,
+				KubeClientSet: fake.NewSimpleClientset(makeSecret()),
+			}
+			header := http.Header{
+				"Content-Type":        []string{"application/json"},
+				"X-Test":              []string{"test-value"},
+				"X-Hub-Signature-256": []string{"sha256=cbc94ae53a181faaefe716fabea750d44ff5ebfc49332712e3fc788cc476bc37"},
+				"X-Hub-Signature":     []string{"sha1=b0be6566f100d803bd01f45764263e4b3cfa1fd5"},
+				"X-Gitlab-Token":      []string{"secret"},
+			}
+			if tt.name == "invalid GitLab token" {
+				header.Set("X-Gitlab-Token", "wrong-token")
 			}
 			request := &http.Request{
 				Body: ioutil.NopCloser(bytes.NewReader(tt.args.payload)),
 				GetBody: func() (io.ReadCloser, error) {
 					return ioutil.NopCloser(bytes.NewReader(tt.args.payload)), nil
 				},
-				Header: http.Header{
-					"Content-Type": []string{"application/json"},
-					"X-Test":       []string{"test-value"},
-				},
+				Header: header,
 			}
 			resp, err := w.ExecuteTrigger(request)
 			if err != nil {
@@ -156,6 +459,75 @@ func TestInterceptor_ExecuteTrigger(t *testing.T) {
 	}
 }
 
+func TestInterceptor_ExecuteTrigger_ContentTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		CEL         *triggersv1.CELInterceptor
+		payload     []byte
+		wantErr     bool
+	}{
+		{
+			name:        "form-encoded body",
+			contentType: "application/x-www-form-urlencoded",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value[0] == 'testing'",
+			},
+			payload: []byte(`value=testing`),
+		},
+		{
+			name:        "yaml body",
+			contentType: "application/yaml",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value == 'testing'",
+			},
+			payload: []byte("value: testing\n"),
+		},
+		{
+			name:        "unknown content type falls back to body_bytes",
+			contentType: "application/octet-stream",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "size(body) == 0 && body_bytes == b'raw-payload'",
+			},
+			payload: []byte("raw-payload"),
+		},
+		{
+			name:        "parseJSON against a form-encoded Slack-style payload",
+			contentType: "application/x-www-form-urlencoded",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "parseJSON(body.payload[0]).text == 'hello'",
+			},
+			payload: []byte(`payload=` + url.QueryEscape(`{"text":"hello"}`)),
+		},
+		{
+			name:        "invalid yaml body",
+			contentType: "application/yaml",
+			CEL: &triggersv1.CELInterceptor{
+				Filter: "body.value == 'testing'",
+			},
+			payload: []byte("value: testing\n\tbad-indent: true\n"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := logging.NewLogger("", "")
+			w := &Interceptor{CEL: tt.CEL, Logger: logger}
+			request := &http.Request{
+				Body: ioutil.NopCloser(bytes.NewReader(tt.payload)),
+				GetBody: func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(bytes.NewReader(tt.payload)), nil
+				},
+				Header: http.Header{"Content-Type": []string{tt.contentType}},
+			}
+			_, err := w.ExecuteTrigger(request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Interceptor.ExecuteTrigger() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestFilterEvaluation(t *testing.T) {
 	jsonMap := map[string]interface{}{
 		"value": "testing",
@@ -182,6 +554,26 @@ func TestFilterEvaluation(t *testing.T) {
 			expr: "body.value == 'testing'",
 			want: types.Bool(true),
 		},
+		{
+			name: "truncate a sha via substring",
+			expr: "body.sha.substring(0, 7)",
+			want: types.String("ec26c3e"),
+		},
+		{
+			name: "split on a character",
+			expr: "body.sha.split('c')[0]",
+			want: types.String("e"),
+		},
+		{
+			name: "compareStrings equal values",
+			expr: "body.value.compareStrings('testing') == 0",
+			want: types.Bool(true),
+		},
+		{
+			name: "jsonpath lookup",
+			expr: "body.jsonpath('value')",
+			want: types.String("testing"),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Tekton Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interceptors
+
+import (
+	"context"
+	"net/http"
+
+	triggersv1 "github.com/tektoncd/triggers/pkg/apis/triggers/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Interceptor is the interface that all interceptors implement.
+type Interceptor interface {
+	ExecuteTrigger(req *http.Request) (*http.Response, error)
+}
+
+// GetSecretToken returns the value of the secret referenced by sr, resolving
+// its namespace against eventListenerNamespace when sr.Namespace is empty.
+func GetSecretToken(ctx context.Context, cs kubernetes.Interface, sr *triggersv1.SecretRef, eventListenerNamespace string) ([]byte, error) {
+	ns := sr.Namespace
+	if ns == "" {
+		ns = eventListenerNamespace
+	}
+	secret, err := cs.CoreV1().Secrets(ns).Get(ctx, sr.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data[sr.SecretKey], nil
+}